@@ -2,19 +2,19 @@ package main
 
 import (
 	"archive/zip"
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"sync"
-	"sync/atomic"
-	"time"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"syscall"
+
+	"github.com/veekram/go-s3-uploader/reporter"
+	"github.com/veekram/go-s3-uploader/storage"
+	"github.com/veekram/go-s3-uploader/uploader"
 )
 
 type TreeNode struct {
@@ -48,71 +48,133 @@ func buildTree(rootPath string) (*TreeNode, error) {
 	return node, nil
 }
 
-func extractZipFiles(zipFilePath, extractPath string) error {
-	err := os.MkdirAll(extractPath, 0755)
-	if err != nil {
-		return err
+// ExtractOptions bounds how extractZipFiles behaves when handed a
+// hostile or malformed archive.
+type ExtractOptions struct {
+	// MaxDepth is how many levels of nested zip-within-zip are followed
+	// before extraction is aborted.
+	MaxDepth int
+
+	// MaxEntryUncompressedSize rejects any single entry that would
+	// expand past this many bytes.
+	MaxEntryUncompressedSize int64
+
+	// MaxTotalUncompressedSize rejects the whole extraction once the
+	// cumulative bytes written (across the archive and any nested
+	// archives) exceeds this.
+	MaxTotalUncompressedSize int64
+
+	// MaxCompressionRatio rejects an entry whose uncompressed size is
+	// more than this many times its compressed size, a hallmark of a
+	// zip bomb.
+	MaxCompressionRatio float64
+}
+
+// DefaultExtractOptions returns conservative zip-bomb thresholds
+// suitable for extracting archives from an untrusted source.
+func DefaultExtractOptions() ExtractOptions {
+	return ExtractOptions{
+		MaxDepth:                 5,
+		MaxEntryUncompressedSize: 10 << 30, // 10 GiB
+		MaxTotalUncompressedSize: 50 << 30, // 50 GiB
+		MaxCompressionRatio:      100,
 	}
+}
 
+// extractZipFiles extracts zipFilePath into extractPath, descending into
+// nested zip entries up to opts.MaxDepth.
+func extractZipFiles(zipFilePath, extractPath string, opts ExtractOptions) error {
 	reader, err := zip.OpenReader(zipFilePath)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
-	for _, file := range reader.File {
+	return extractEntries(&reader.Reader, extractPath, opts, 0, new(int64))
+}
+
+// extractNestedZip opens the zip already written to zipFilePath (as a
+// nested entry of a parent archive) via os.Open+Stat rather than
+// recursing through extractZipFiles, so the recursion depth tracked in
+// opts.MaxDepth is the only thing bounding how deep nested archives go.
+func extractNestedZip(zipFilePath, extractPath string, opts ExtractOptions, depth int, totalExtracted *int64) error {
+	f, err := os.Open(zipFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	return extractEntries(zr, extractPath, opts, depth, totalExtracted)
+}
+
+func extractEntries(zr *zip.Reader, extractPath string, opts ExtractOptions, depth int, totalExtracted *int64) error {
+	if depth > opts.MaxDepth {
+		return fmt.Errorf("extractZipFiles: nested zip depth exceeds limit of %d", opts.MaxDepth)
+	}
+
+	if err := os.MkdirAll(extractPath, 0755); err != nil {
+		return err
+	}
+
+	for _, file := range zr.File {
 		// Skip files and directories under __MACOSX
 		if strings.HasPrefix(file.Name, "__MACOSX/") {
 			continue
 		}
 
-		path := filepath.Join(extractPath, file.Name)
-
-		// Ensure the extracted file path is within the designated extraction directory
-		if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(extractPath)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal file path: %s", file.Name)
+		destPath, err := safeJoin(extractPath, file.Name)
+		if err != nil {
+			return err
 		}
 
-		if file.FileInfo().IsDir() {
-			err := os.MkdirAll(path, file.Mode())
-			if err != nil {
+		switch {
+		case file.Mode()&os.ModeSymlink != 0:
+			// Resolving a symlink target safely would require
+			// re-validating it against extractPath after every
+			// extraction step, so we simply skip symlink entries
+			// instead of following them.
+			continue
+
+		case file.FileInfo().IsDir():
+			if err := os.MkdirAll(destPath, file.Mode()); err != nil {
 				return err
 			}
 			continue
 		}
 
-		// Create parent directories if they don't exist
-		err = os.MkdirAll(filepath.Dir(path), 0755)
-		if err != nil {
-			return err
-		}
-
-		srcFile, err := file.Open()
-		if err != nil {
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 			return err
 		}
-		defer srcFile.Close()
 
-		destFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+		written, err := extractEntry(file, destPath, opts, totalExtracted)
 		if err != nil {
 			return err
 		}
-		defer destFile.Close()
 
-		_, err = io.Copy(destFile, srcFile)
-		if err != nil {
-			return err
+		if opts.MaxCompressionRatio > 0 && file.CompressedSize64 > 0 {
+			ratio := float64(written) / float64(file.CompressedSize64)
+			if ratio > opts.MaxCompressionRatio {
+				return fmt.Errorf("extractZipFiles: %s exceeds max compression ratio (%.1fx)", file.Name, ratio)
+			}
 		}
 
 		// Extract recursively if the file is a zip file
 		if strings.HasSuffix(file.Name, ".zip") {
-			err = extractZipFiles(path, filepath.Dir(path))
-			if err != nil {
+			if err := extractNestedZip(destPath, filepath.Dir(destPath), opts, depth+1, totalExtracted); err != nil {
 				return err
 			}
 			// Delete the zip file after extraction
-			err = os.Remove(path)
-			if err != nil {
+			if err := os.Remove(destPath); err != nil {
 				return err
 			}
 		}
@@ -121,96 +183,109 @@ func extractZipFiles(zipFilePath, extractPath string) error {
 	return nil
 }
 
-func printTree(node *TreeNode, level int) {
-	indent := strings.Repeat("  ", level)
-	fmt.Println(indent + node.Name)
-
-	for _, child := range node.Children {
-		printTree(child, level+1)
+// extractEntry writes a single zip entry to destPath, enforcing the
+// per-entry and cumulative size limits in opts. Unlike looping with
+// deferred closes, each call closes its own source and destination
+// files as soon as the entry is done, so a large archive doesn't pile
+// up open file descriptors.
+func extractEntry(file *zip.File, destPath string, opts ExtractOptions, totalExtracted *int64) (int64, error) {
+	srcFile, err := file.Open()
+	if err != nil {
+		return 0, err
 	}
-}
+	defer srcFile.Close()
 
-func uploadFileToS3(s3Client *s3.S3, bucketName, filePath, key string) error {
-	file, err := os.Open(filePath)
+	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer file.Close()
+	defer destFile.Close()
 
-	_, err = s3Client.PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-		Body:   file,
-	})
-	if err != nil {
-		return err
+	w := &limitedWriter{
+		w:        destFile,
+		maxEntry: opts.MaxEntryUncompressedSize,
+		total:    totalExtracted,
+		maxTotal: opts.MaxTotalUncompressedSize,
 	}
 
-	return nil
+	written, err := io.Copy(w, srcFile)
+	if err != nil {
+		return written, fmt.Errorf("extracting %s: %w", file.Name, err)
+	}
+	return written, nil
 }
 
-func uploadDirectoryToS3(s3Client *s3.S3, bucketName, directoryPath, prefix string, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	var totalFiles int64
-	var uploadedFiles int64
-	startTime := time.Now()
-
-	err := filepath.Walk(directoryPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
-
-		totalFiles++ // Increment total files count
+// safeJoin joins name onto base and rejects the result if name tries to
+// escape base via ".." components. filepath.Rel is used instead of a
+// HasPrefix string check, which is not reliable on Windows.
+func safeJoin(base, name string) (string, error) {
+	dest := filepath.Join(base, name)
 
-		// Determine the S3 key based on the prefix and relative path
-		relativePath := strings.TrimPrefix(path, directoryPath)
-		key := filepath.Join(prefix, relativePath)
+	rel, err := filepath.Rel(base, dest)
+	if err != nil {
+		return "", fmt.Errorf("illegal file path: %s", name)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal file path: %s", name)
+	}
 
-		fileStartTime := time.Now() // Start time for current file upload
+	return dest, nil
+}
 
-		// Upload the file to S3
-		err = uploadFileToS3(s3Client, bucketName, path, key)
-		if err != nil {
-			return err
-		}
+// limitedWriter wraps an io.Writer and aborts once either the bytes
+// written through it, or the shared running total, exceed their limit.
+// A limit of 0 means unbounded.
+type limitedWriter struct {
+	w        io.Writer
+	maxEntry int64
+	written  int64
+	total    *int64
+	maxTotal int64
+}
 
-		fileEndTime := time.Now() // End time for current file upload
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	l.written += int64(len(p))
+	*l.total += int64(len(p))
 
-		// Increment uploaded files count and update progress
-		atomic.AddInt64(&uploadedFiles, 1)
-		printProgress(uploadedFiles, totalFiles, path, fileStartTime, fileEndTime)
+	if l.maxEntry > 0 && l.written > l.maxEntry {
+		return 0, fmt.Errorf("entry exceeds max uncompressed size of %d bytes", l.maxEntry)
+	}
+	if l.maxTotal > 0 && *l.total > l.maxTotal {
+		return 0, fmt.Errorf("extraction exceeds total uncompressed size limit of %d bytes", l.maxTotal)
+	}
 
-		return nil
-	})
+	return l.w.Write(p)
+}
 
-	endTime := time.Now()
-	elapsedTime := endTime.Sub(startTime)
+func printTree(node *TreeNode, level int) {
+	indent := strings.Repeat("  ", level)
+	fmt.Println(indent + node.Name)
 
-	if err != nil {
-		fmt.Printf("Error uploading directory %s: %s\n", directoryPath, err.Error())
-	} else {
-		fmt.Printf("\nUploaded directory %s\n", directoryPath)
-		fmt.Printf("Total upload time: %s\n", elapsedTime.String())
+	for _, child := range node.Children {
+		printTree(child, level+1)
 	}
 }
 
-func printProgress(uploadedFiles, totalFiles int64, fileName string, startTime, endTime time.Time) {
-	progress := float64(uploadedFiles) / float64(totalFiles) * 100
-	fileTime := endTime.Sub(startTime)
-	fmt.Printf("\rUploading: %.2f%% (%d/%d) - %s - Time: %s", progress, uploadedFiles, totalFiles, fileName, fileTime.String())
-}
-
 func main() {
+	endpoint := flag.String("endpoint", "", "custom S3-compatible endpoint (e.g. for MinIO, Ceph, Wasabi)")
+	region := flag.String("region", "us-east-1", "AWS region")
+	forcePathStyle := flag.Bool("force-path-style", false, "address buckets as endpoint/bucket instead of bucket.endpoint")
+	disableSSL := flag.Bool("disable-ssl", false, "talk to the endpoint over plain HTTP")
+	insecureSkipVerify := flag.Bool("insecure-skip-verify", false, "skip TLS certificate verification (self-signed endpoints)")
+	roleARN := flag.String("role-arn", "", "IAM role to assume via STS on top of the default credential chain")
+	jsonProgress := flag.Bool("json-progress", false, "emit JSON-lines progress events instead of a TTY progress bar (for CI)")
+	sync := flag.Bool("sync", false, "only upload files that changed since the last sync, instead of re-uploading everything")
+	deleteExtra := flag.Bool("delete", false, "with --sync, remove remote objects that no longer exist locally")
+	dryRun := flag.Bool("dry-run", false, "with --sync, print the sync plan without uploading or deleting anything")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	zipFilePath := "ziptest/<big-zip-file>" // Replace with the path to the zip file
 	extractPath := "ziptest/extracted"      // Replace with the desired extraction directory
 
-	err := extractZipFiles(zipFilePath, extractPath)
+	err := extractZipFiles(zipFilePath, extractPath, DefaultExtractOptions())
 	if err != nil {
 		fmt.Println("Error extracting zip files:", err)
 		return
@@ -224,20 +299,19 @@ func main() {
 
 	printTree(tree, 0)
 
-	// Specify your AWS credentials and region
-	awsConfig := &aws.Config{
-		Region:      aws.String("S3-REGION"),
-		Credentials: credentials.NewStaticCredentials("S3-ACCESS-ID", "S3-ACCESS-SECRET", ""),
-	}
-	sess, err := session.NewSession(awsConfig)
+	sess, err := storage.NewSession(storage.Config{
+		Region:             *region,
+		Endpoint:           *endpoint,
+		ForcePathStyle:     *forcePathStyle,
+		DisableSSL:         *disableSSL,
+		InsecureSkipVerify: *insecureSkipVerify,
+		RoleARN:            *roleARN,
+	})
 	if err != nil {
 		fmt.Println("Failed to create AWS session:", err)
 		return
 	}
 
-	// Create an S3 client
-	s3Client := s3.New(sess)
-
 	// Specify your S3 bucket name
 	bucketName := "S3-BUCKET-NAME"
 
@@ -247,11 +321,60 @@ func main() {
 	// Specify the prefix for S3 keys (optional)
 	uploadPrefix := "uploads/"
 
-	// Concurrently upload the directory to S3
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go uploadDirectoryToS3(s3Client, bucketName, directoryPath, uploadPrefix, &wg)
+	var rep reporter.Reporter
+	if *jsonProgress {
+		rep = reporter.NewJSONLReporter(os.Stdout)
+	} else {
+		rep = reporter.NewTTYReporter()
+	}
+
+	// Upload the directory to S3 with bounded concurrency, multipart
+	// uploads, and resume support via the on-disk manifest.
+	up, err := uploader.New(sess, uploader.Config{
+		Bucket:      bucketName,
+		Concurrency: 4,
+		Reporter:    rep,
+	})
+	if err != nil {
+		fmt.Println("Failed to create uploader:", err)
+		return
+	}
+
+	if *sync {
+		plan, err := up.Sync(ctx, directoryPath, uploadPrefix, uploader.SyncOptions{
+			Delete: *deleteExtra,
+			DryRun: *dryRun,
+		})
+		if err != nil {
+			fmt.Println("Error syncing directory:", err)
+			return
+		}
+		printSyncPlan(plan, *dryRun)
+		return
+	}
+
+	if err := up.UploadDirectory(ctx, directoryPath, uploadPrefix); err != nil {
+		fmt.Println("Error uploading directory:", err)
+		return
+	}
+}
+
+func printSyncPlan(plan *uploader.SyncPlan, dryRun bool) {
+	verb := "will upload"
+	if !dryRun {
+		verb = "uploaded"
+	}
+	fmt.Printf("%s %d file(s):\n", verb, len(plan.ToUpload))
+	for _, key := range plan.ToUpload {
+		fmt.Printf("  + %s\n", key)
+	}
 
-	// Wait for the upload to complete
-	wg.Wait()
+	verb = "will delete"
+	if !dryRun {
+		verb = "deleted"
+	}
+	fmt.Printf("%s %d remote object(s):\n", verb, len(plan.ToDelete))
+	for _, key := range plan.ToDelete {
+		fmt.Printf("  - %s\n", key)
+	}
 }
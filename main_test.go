@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSafeJoin(t *testing.T) {
+	base := t.TempDir()
+
+	cases := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"plain file", "foo.txt", false},
+		{"nested dir", "a/b/c.txt", false},
+		{"parent traversal", "../escape.txt", true},
+		{"nested parent traversal", "a/../../escape.txt", true},
+		{"rooted entry stays under base", "/etc/passwd", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := safeJoin(base, c.entry)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("safeJoin(%q, %q) = %q, nil; want error", base, c.entry, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("safeJoin(%q, %q) unexpected error: %v", base, c.entry, err)
+			}
+			if !strings.HasPrefix(got, base) {
+				t.Fatalf("safeJoin(%q, %q) = %q; escapes base", base, c.entry, got)
+			}
+		})
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	t.Run("under limits writes through", func(t *testing.T) {
+		var buf bytes.Buffer
+		total := new(int64)
+		w := &limitedWriter{w: &buf, maxEntry: 100, total: total, maxTotal: 100}
+
+		n, err := w.Write([]byte("hello"))
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if n != 5 {
+			t.Fatalf("Write returned n=%d, want 5", n)
+		}
+		if buf.String() != "hello" {
+			t.Fatalf("buffer = %q, want %q", buf.String(), "hello")
+		}
+		if *total != 5 {
+			t.Fatalf("total = %d, want 5", *total)
+		}
+	})
+
+	t.Run("exceeding per-entry limit errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		total := new(int64)
+		w := &limitedWriter{w: &buf, maxEntry: 3, total: total}
+
+		if _, err := w.Write([]byte("hello")); err == nil {
+			t.Fatal("expected error when entry exceeds maxEntry, got nil")
+		}
+	})
+
+	t.Run("exceeding shared total limit errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		total := new(int64)
+		*total = 8
+		w := &limitedWriter{w: &buf, total: total, maxTotal: 10}
+
+		if _, err := w.Write([]byte("hello")); err == nil {
+			t.Fatal("expected error when shared total exceeds maxTotal, got nil")
+		}
+	})
+
+	t.Run("zero limits are unbounded", func(t *testing.T) {
+		var buf bytes.Buffer
+		total := new(int64)
+		w := &limitedWriter{w: &buf, total: total}
+
+		if _, err := w.Write(bytes.Repeat([]byte("x"), 1<<20)); err != nil {
+			t.Fatalf("unexpected error with zero limits: %v", err)
+		}
+	})
+}
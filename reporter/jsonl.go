@@ -0,0 +1,85 @@
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSONLReporter writes one JSON object per line for each upload event,
+// suitable for piping into CI logs or a log aggregator. Its methods are
+// called concurrently by every upload worker, so writes are
+// serialized with a mutex to keep lines from interleaving.
+type JSONLReporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLReporter returns a Reporter that writes newline-delimited JSON
+// events to w.
+func NewJSONLReporter(w io.Writer) *JSONLReporter {
+	return &JSONLReporter{w: w, enc: json.NewEncoder(w)}
+}
+
+type jsonlEvent struct {
+	Event      string `json:"event"`
+	Key        string `json:"key,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	TotalFiles  int64 `json:"total_files,omitempty"`
+	TotalBytes  int64 `json:"total_bytes,omitempty"`
+	DoneFiles   int64 `json:"done_files,omitempty"`
+	DoneBytes   int64 `json:"done_bytes,omitempty"`
+	FailedFiles int64 `json:"failed_files,omitempty"`
+	ElapsedMS   int64 `json:"elapsed_ms,omitempty"`
+}
+
+func (r *JSONLReporter) Start(stats BatchStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(jsonlEvent{
+		Event:      "batch_start",
+		TotalFiles: stats.TotalFiles,
+		TotalBytes: stats.TotalBytes,
+	})
+}
+
+func (r *JSONLReporter) OnFileStart(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(jsonlEvent{Event: "file_start", Key: key})
+}
+
+func (r *JSONLReporter) OnFileDone(key string, size int64, duration time.Duration, err error) {
+	ev := jsonlEvent{
+		Event:      "file_done",
+		Key:        key,
+		Size:       size,
+		DurationMS: duration.Milliseconds(),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(ev)
+}
+
+func (r *JSONLReporter) OnBatchDone(stats BatchStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(jsonlEvent{
+		Event:       "batch_done",
+		TotalFiles:  stats.TotalFiles,
+		TotalBytes:  stats.TotalBytes,
+		DoneFiles:   stats.DoneFiles,
+		DoneBytes:   stats.DoneBytes,
+		FailedFiles: stats.FailedFiles,
+		ElapsedMS:   stats.Elapsed.Milliseconds(),
+	})
+}
@@ -0,0 +1,52 @@
+// Package reporter defines a pluggable interface for surfacing upload
+// progress, decoupling the transfer logic in the uploader package from
+// how that progress is displayed.
+package reporter
+
+import "time"
+
+// BatchStats summarizes a batch of file uploads. TotalFiles and
+// TotalBytes are known up front from a pre-walk sizing pass, so
+// implementations can compute accurate throughput and ETA instead of
+// guessing from a running count.
+type BatchStats struct {
+	TotalFiles  int64
+	TotalBytes  int64
+	DoneFiles   int64
+	DoneBytes   int64
+	FailedFiles int64
+	Elapsed     time.Duration
+}
+
+// Reporter receives upload lifecycle events. Implementations must be
+// safe for concurrent use: OnFileStart and OnFileDone are called from
+// every upload worker goroutine.
+type Reporter interface {
+	// Start is called once, after the pre-walk sizing pass, with
+	// TotalFiles and TotalBytes populated.
+	Start(stats BatchStats)
+
+	// OnFileStart is called when a worker begins uploading key.
+	OnFileStart(key string)
+
+	// OnFileDone is called when key finishes uploading, successfully or
+	// not. size is the file's size in bytes, duration is how long the
+	// upload took.
+	OnFileDone(key string, size int64, duration time.Duration, err error)
+
+	// OnBatchDone is called once, after every file has been attempted,
+	// with final totals.
+	OnBatchDone(stats BatchStats)
+}
+
+// noop is a Reporter that does nothing, used when no Reporter is
+// configured.
+type noop struct{}
+
+// Noop returns a Reporter whose methods are all no-ops.
+func Noop() Reporter { return noop{} }
+
+func (noop) Start(BatchStats)                               {}
+func (noop) OnFileStart(string)                             {}
+func (noop) OnFileDone(string, int64, time.Duration, error) {}
+func (noop) OnBatchDone(BatchStats)                         {}
@@ -0,0 +1,50 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// TTYReporter renders a single progress bar tracking cumulative bytes
+// uploaded, with throughput and ETA computed by the underlying
+// progressbar widget from the total set in Start.
+type TTYReporter struct {
+	bar *progressbar.ProgressBar
+}
+
+// NewTTYReporter returns a Reporter that draws a progress bar to
+// os.Stderr (the progressbar package's default).
+func NewTTYReporter() *TTYReporter {
+	return &TTYReporter{}
+}
+
+func (r *TTYReporter) Start(stats BatchStats) {
+	r.bar = progressbar.DefaultBytes(stats.TotalBytes, "uploading")
+}
+
+func (r *TTYReporter) OnFileStart(key string) {
+	if r.bar != nil {
+		r.bar.Describe(fmt.Sprintf("uploading %s", key))
+	}
+}
+
+func (r *TTYReporter) OnFileDone(key string, size int64, duration time.Duration, err error) {
+	if r.bar == nil {
+		return
+	}
+	if err != nil {
+		r.bar.Describe(fmt.Sprintf("failed %s: %s", key, err))
+		return
+	}
+	r.bar.Add64(size)
+}
+
+func (r *TTYReporter) OnBatchDone(stats BatchStats) {
+	if r.bar == nil {
+		return
+	}
+	r.bar.Finish()
+	fmt.Printf("\nuploaded %d/%d files (%d failed) in %s\n", stats.DoneFiles, stats.TotalFiles, stats.FailedFiles, stats.Elapsed.Round(time.Millisecond))
+}
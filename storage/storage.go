@@ -0,0 +1,86 @@
+// Package storage builds the AWS session used to talk to S3 (or an
+// S3-compatible service such as MinIO, Ceph, or Wasabi).
+package storage
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Config describes how to reach the target S3-compatible endpoint and
+// how to authenticate against it.
+type Config struct {
+	// Region is the AWS region, or any non-empty placeholder required by
+	// S3-compatible services that ignore region but still expect one.
+	Region string
+
+	// Endpoint overrides the default AWS S3 endpoint, e.g.
+	// "https://minio.example.com:9000" for a self-hosted MinIO/Ceph/Wasabi
+	// deployment.
+	Endpoint string
+
+	// ForcePathStyle addresses buckets as endpoint/bucket instead of
+	// bucket.endpoint, which most non-AWS S3-compatible services require.
+	ForcePathStyle bool
+
+	// DisableSSL talks to Endpoint over plain HTTP.
+	DisableSSL bool
+
+	// InsecureSkipVerify skips TLS certificate verification, for
+	// endpoints using self-signed certificates.
+	InsecureSkipVerify bool
+
+	// RoleARN, if set, is assumed via STS on top of whatever credentials
+	// the default provider chain resolves (environment variables, the
+	// shared credentials/config files, or an EC2/ECS/EKS instance role).
+	RoleARN string
+}
+
+// NewSession builds a session.Session for cfg. Credentials are resolved
+// by the SDK's default provider chain (environment variables, shared
+// credentials file, then EC2/ECS/EKS instance role), optionally wrapped
+// in an STS AssumeRole if cfg.RoleARN is set. No credentials are ever
+// hardcoded.
+func NewSession(cfg Config) (*session.Session, error) {
+	awsCfg := aws.NewConfig()
+
+	if cfg.Region != "" {
+		awsCfg = awsCfg.WithRegion(cfg.Region)
+	}
+	if cfg.Endpoint != "" {
+		awsCfg = awsCfg.WithEndpoint(cfg.Endpoint)
+	}
+	if cfg.ForcePathStyle {
+		awsCfg = awsCfg.WithS3ForcePathStyle(true)
+	}
+	if cfg.DisableSSL {
+		awsCfg = awsCfg.WithDisableSSL(true)
+	}
+	if cfg.InsecureSkipVerify {
+		awsCfg = awsCfg.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		})
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsCfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.RoleARN != "" {
+		sess = sess.Copy(&aws.Config{
+			Credentials: stscreds.NewCredentials(sess, cfg.RoleARN),
+		})
+	}
+
+	return sess, nil
+}
@@ -0,0 +1,203 @@
+package uploader
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// SyncOptions controls how Sync reconciles a local directory with its
+// remote counterpart.
+type SyncOptions struct {
+	// Delete removes remote objects under prefix that have no matching
+	// local file.
+	Delete bool
+
+	// DryRun builds and returns the SyncPlan without uploading or
+	// deleting anything.
+	DryRun bool
+}
+
+// SyncPlan is the set of changes Sync intends to make (or, with
+// DryRun, the set of changes it would have made).
+type SyncPlan struct {
+	ToUpload []string
+	ToDelete []string
+}
+
+// remoteObject is what we know about an object already in the bucket.
+type remoteObject struct {
+	ETag string
+	Size int64
+}
+
+// Sync reconciles localDir with bucket/prefix: it lists the existing
+// remote objects, diffs them against the local tree, and uploads only
+// what changed. Single-part objects (whose ETag is a plain MD5) are
+// diffed by content; multipart objects are diffed against our own
+// resume manifest by size and mtime, since their ETag is not a plain
+// MD5 of the file contents. With opts.Delete, remote objects under
+// prefix that no longer exist locally are removed.
+func (u *Uploader) Sync(ctx context.Context, localDir, prefix string, opts SyncOptions) (*SyncPlan, error) {
+	remote, err := u.listRemote(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("uploader: listing remote objects: %w", err)
+	}
+
+	plan := &SyncPlan{}
+	var jobs []fileJob
+	localKeys := make(map[string]struct{})
+
+	walkErr := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath := strings.TrimPrefix(path, localDir)
+		key := filepath.ToSlash(filepath.Join(prefix, relPath))
+		localKeys[key] = struct{}{}
+
+		changed, err := u.hasChanged(path, key, info, remote)
+		if err != nil {
+			return err
+		}
+		if changed {
+			plan.ToUpload = append(plan.ToUpload, key)
+			jobs = append(jobs, fileJob{path: path, key: key, info: info})
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	if opts.Delete {
+		for key := range remote {
+			if _, ok := localKeys[key]; !ok {
+				plan.ToDelete = append(plan.ToDelete, key)
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	if err := u.uploadFiles(ctx, jobs); err != nil {
+		return plan, err
+	}
+	if len(plan.ToDelete) > 0 {
+		if err := u.deleteRemote(ctx, plan.ToDelete); err != nil {
+			return plan, fmt.Errorf("uploader: deleting remote objects: %w", err)
+		}
+	}
+
+	return plan, nil
+}
+
+// hasChanged reports whether the local file at path needs to be
+// (re-)uploaded to key, given what we know about the matching remote
+// object (if any).
+func (u *Uploader) hasChanged(path, key string, info os.FileInfo, remote map[string]remoteObject) (bool, error) {
+	obj, ok := remote[key]
+	if !ok {
+		return true, nil
+	}
+	if obj.Size != info.Size() {
+		return true, nil
+	}
+
+	// A plain (non-multipart) ETag is the MD5 of the object body in hex,
+	// quoted, with no "-N" suffix. Compare it directly to the local MD5.
+	if !strings.Contains(obj.ETag, "-") {
+		sum, err := md5File(path)
+		if err != nil {
+			return false, err
+		}
+		return sum != obj.ETag, nil
+	}
+
+	// Multipart ETags aren't a content hash we can reproduce locally, so
+	// fall back to the resume manifest's recorded size+mtime.
+	return !u.alreadyUploaded(key, info), nil
+}
+
+// listRemote pages through ListObjectsV2 under prefix and returns a map
+// of key -> remoteObject.
+func (u *Uploader) listRemote(ctx context.Context, prefix string) (map[string]remoteObject, error) {
+	remote := make(map[string]remoteObject)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(u.cfg.Bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int64(1000),
+	}
+
+	err := u.svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			remote[aws.StringValue(obj.Key)] = remoteObject{
+				ETag: strings.Trim(aws.StringValue(obj.ETag), `"`),
+				Size: aws.Int64Value(obj.Size),
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return remote, nil
+}
+
+// deleteRemote removes the given keys from the bucket in batches of up
+// to 1000, the limit accepted by a single DeleteObjects call.
+func (u *Uploader) deleteRemote(ctx context.Context, keys []string) error {
+	const batchSize = 1000
+
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		objects := make([]*s3.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		_, err := u.svc.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(u.cfg.Bucket),
+			Delete: &s3.Delete{Objects: objects},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func md5File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
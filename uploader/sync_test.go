@@ -0,0 +1,118 @@
+package uploader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func newTestUploader(t *testing.T) *Uploader {
+	t.Helper()
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+	if err != nil {
+		t.Fatalf("session.NewSession: %v", err)
+	}
+
+	u, err := New(sess, Config{
+		Bucket:       "test-bucket",
+		ManifestPath: filepath.Join(t.TempDir(), "manifest.json"),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return u
+}
+
+func TestHasChanged(t *testing.T) {
+	u := newTestUploader(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	sum, err := md5File(path)
+	if err != nil {
+		t.Fatalf("md5File: %v", err)
+	}
+
+	t.Run("no remote entry uploads", func(t *testing.T) {
+		changed, err := u.hasChanged(path, "file.txt", info, map[string]remoteObject{})
+		if err != nil {
+			t.Fatalf("hasChanged: %v", err)
+		}
+		if !changed {
+			t.Error("expected changed=true when remote has no entry")
+		}
+	})
+
+	t.Run("size differs uploads", func(t *testing.T) {
+		remote := map[string]remoteObject{"file.txt": {ETag: "deadbeef", Size: info.Size() + 1}}
+		changed, err := u.hasChanged(path, "file.txt", info, remote)
+		if err != nil {
+			t.Fatalf("hasChanged: %v", err)
+		}
+		if !changed {
+			t.Error("expected changed=true on size mismatch")
+		}
+	})
+
+	t.Run("single-part etag match skips", func(t *testing.T) {
+		remote := map[string]remoteObject{"file.txt": {ETag: sum, Size: info.Size()}}
+		changed, err := u.hasChanged(path, "file.txt", info, remote)
+		if err != nil {
+			t.Fatalf("hasChanged: %v", err)
+		}
+		if changed {
+			t.Error("expected changed=false when local md5 matches remote etag")
+		}
+	})
+
+	t.Run("single-part etag mismatch uploads", func(t *testing.T) {
+		remote := map[string]remoteObject{"file.txt": {ETag: "0000000000000000000000000000000", Size: info.Size()}}
+		changed, err := u.hasChanged(path, "file.txt", info, remote)
+		if err != nil {
+			t.Fatalf("hasChanged: %v", err)
+		}
+		if !changed {
+			t.Error("expected changed=true when local md5 mismatches remote etag")
+		}
+	})
+
+	t.Run("multipart etag with matching manifest skips", func(t *testing.T) {
+		key := "multipart.txt"
+		u.manifest[key] = ManifestEntry{Key: key, Size: info.Size(), MTime: info.ModTime()}
+		remote := map[string]remoteObject{key: {ETag: "aaaaaaaa-2", Size: info.Size()}}
+
+		changed, err := u.hasChanged(path, key, info, remote)
+		if err != nil {
+			t.Fatalf("hasChanged: %v", err)
+		}
+		if changed {
+			t.Error("expected changed=false when manifest matches size+mtime")
+		}
+	})
+
+	t.Run("multipart etag with stale manifest uploads", func(t *testing.T) {
+		key := "multipart2.txt"
+		u.manifest[key] = ManifestEntry{Key: key, Size: info.Size() + 1, MTime: info.ModTime()}
+		remote := map[string]remoteObject{key: {ETag: "aaaaaaaa-2", Size: info.Size()}}
+
+		changed, err := u.hasChanged(path, key, info, remote)
+		if err != nil {
+			t.Fatalf("hasChanged: %v", err)
+		}
+		if !changed {
+			t.Error("expected changed=true when manifest is stale")
+		}
+	})
+}
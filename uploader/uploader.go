@@ -0,0 +1,334 @@
+// Package uploader provides a worker-pool based uploader that pushes a
+// local directory tree to S3 using multipart uploads, with an on-disk
+// manifest so interrupted runs can resume without re-uploading files that
+// already made it to the bucket.
+package uploader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/veekram/go-s3-uploader/reporter"
+)
+
+// Config controls how an Uploader performs multipart uploads.
+type Config struct {
+	Bucket string
+
+	// Concurrency bounds how many files are uploaded in parallel. It is
+	// also used as s3manager's per-upload part concurrency, so up to
+	// Concurrency*Concurrency parts can be in flight at once. It does not
+	// change how many parts a single file is split into; that is
+	// controlled by PartSize.
+	Concurrency int
+
+	// PartSize is the size, in bytes, of each part in a multipart upload.
+	// Defaults to s3manager.DefaultUploadPartSize if zero.
+	PartSize int64
+
+	// ManifestPath is where the resume manifest is read from and written
+	// to. Defaults to ".s3uploader-manifest.json" in the current
+	// directory if empty.
+	ManifestPath string
+
+	// Reporter receives upload progress events. If nil, events are
+	// discarded.
+	Reporter reporter.Reporter
+}
+
+// ManifestEntry records enough information about a previously uploaded
+// file to decide whether it can be skipped on a later run.
+type ManifestEntry struct {
+	Key   string    `json:"key"`
+	ETag  string    `json:"etag"`
+	Size  int64     `json:"size"`
+	MTime time.Time `json:"mtime"`
+}
+
+// Uploader uploads a directory tree to S3 with bounded concurrency,
+// multipart uploads, and resume support via an on-disk manifest.
+type Uploader struct {
+	cfg      Config
+	s3       *s3manager.Uploader
+	svc      *s3.S3
+	manifest map[string]ManifestEntry
+	mu       sync.Mutex
+}
+
+// fileJob describes a single local file queued for upload.
+type fileJob struct {
+	path string
+	key  string
+	info os.FileInfo
+}
+
+// New creates an Uploader backed by the given session. Defaults are
+// applied to Config fields that are left unset.
+func New(sess *session.Session, cfg Config) (*Uploader, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("uploader: bucket is required")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.ManifestPath == "" {
+		cfg.ManifestPath = ".s3uploader-manifest.json"
+	}
+	if cfg.Reporter == nil {
+		cfg.Reporter = reporter.Noop()
+	}
+
+	u := &Uploader{
+		cfg: cfg,
+		s3: s3manager.NewUploader(sess, func(up *s3manager.Uploader) {
+			if cfg.PartSize > 0 {
+				up.PartSize = cfg.PartSize
+			}
+			up.Concurrency = cfg.Concurrency
+			up.LeavePartsOnError = true
+		}),
+		svc: s3.New(sess),
+	}
+
+	manifest, err := loadManifest(cfg.ManifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("uploader: loading manifest: %w", err)
+	}
+	u.manifest = manifest
+
+	return u, nil
+}
+
+// UploadDirectory walks dir and uploads every regular file to bucket under
+// prefix, using cfg.Concurrency workers. Files whose manifest entry still
+// matches their current size and mtime are skipped.
+func (u *Uploader) UploadDirectory(ctx context.Context, dir, prefix string) error {
+	var jobs []fileJob
+
+	walkErr := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath := strings.TrimPrefix(path, dir)
+		key := filepath.ToSlash(filepath.Join(prefix, relPath))
+
+		if u.alreadyUploaded(key, info) {
+			return nil
+		}
+
+		jobs = append(jobs, fileJob{path: path, key: key, info: info})
+		return nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	return u.uploadFiles(ctx, jobs)
+}
+
+// batchCounters are the running totals for a single uploadFiles call,
+// updated atomically by every worker goroutine.
+type batchCounters struct {
+	doneFiles   int64
+	doneBytes   int64
+	failedFiles int64
+}
+
+// uploadFiles uploads the given jobs using cfg.Concurrency workers and
+// blocks until they all complete or one of them errors. The full job
+// list is known up front (the caller's sizing pass), so cfg.Reporter
+// gets an accurate total byte count to compute throughput and ETA from,
+// rather than a count that only becomes meaningful once the walk
+// finishes. Once any worker errors, the batch context is cancelled so
+// the remaining uploads abort quickly; workers keep draining jobCh
+// (rather than exiting) so the send loop below always has a receiver
+// and can't deadlock on the unbuffered channel. The manifest is saved
+// before returning regardless of outcome, so files that completed
+// before an error or cancellation are not re-uploaded on the next run.
+func (u *Uploader) uploadFiles(ctx context.Context, jobs []fileJob) error {
+	rep := u.cfg.Reporter
+
+	var totalBytes int64
+	for _, j := range jobs {
+		totalBytes += j.info.Size()
+	}
+	rep.Start(reporter.BatchStats{TotalFiles: int64(len(jobs)), TotalBytes: totalBytes})
+	batchStart := time.Now()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobCh := make(chan fileJob)
+	errs := make(chan error, len(jobs))
+	var stats batchCounters
+
+	var wg sync.WaitGroup
+	for i := 0; i < u.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobCh {
+				select {
+				case <-ctx.Done():
+					// A prior error already cancelled the batch; keep
+					// draining jobCh without uploading so the send
+					// loop below never blocks on a channel nothing is
+					// receiving from.
+					continue
+				default:
+				}
+
+				rep.OnFileStart(j.key)
+				fileStart := time.Now()
+				err := u.uploadOne(ctx, j.path, j.key, j.info)
+				rep.OnFileDone(j.key, j.info.Size(), time.Since(fileStart), err)
+
+				if err != nil {
+					atomic.AddInt64(&stats.failedFiles, 1)
+					errs <- fmt.Errorf("uploading %s: %w", j.path, err)
+					cancel()
+					continue
+				}
+				atomic.AddInt64(&stats.doneFiles, 1)
+				atomic.AddInt64(&stats.doneBytes, j.info.Size())
+			}
+		}()
+	}
+
+sendLoop:
+	for _, j := range jobs {
+		select {
+		case jobCh <- j:
+		case <-ctx.Done():
+			break sendLoop
+		}
+	}
+	close(jobCh)
+	wg.Wait()
+	close(errs)
+
+	var firstErr error
+	for err := range errs {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+
+	rep.OnBatchDone(reporter.BatchStats{
+		TotalFiles:  int64(len(jobs)),
+		TotalBytes:  totalBytes,
+		DoneFiles:   atomic.LoadInt64(&stats.doneFiles),
+		DoneBytes:   atomic.LoadInt64(&stats.doneBytes),
+		FailedFiles: atomic.LoadInt64(&stats.failedFiles),
+		Elapsed:     time.Since(batchStart),
+	})
+
+	// Save whatever progress was made even on error or cancellation, so a
+	// rerun can resume from the files that did complete instead of
+	// re-uploading the whole batch.
+	if err := u.saveManifest(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// alreadyUploaded reports whether key has a manifest entry matching
+// info's current size and mtime.
+func (u *Uploader) alreadyUploaded(key string, info os.FileInfo) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry, ok := u.manifest[key]
+	if !ok {
+		return false
+	}
+	return entry.Size == info.Size() && entry.MTime.Equal(info.ModTime())
+}
+
+func (u *Uploader) uploadOne(ctx context.Context, path, key string, info os.FileInfo) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	out, err := u.s3.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(u.cfg.Bucket),
+		Key:    aws.String(key),
+		Body:   file,
+	})
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	u.manifest[key] = ManifestEntry{
+		Key:   key,
+		ETag:  strings.Trim(aws.StringValue(out.ETag), `"`),
+		Size:  info.Size(),
+		MTime: info.ModTime(),
+	}
+	u.mu.Unlock()
+
+	return nil
+}
+
+func loadManifest(path string) (map[string]ManifestEntry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return make(map[string]ManifestEntry), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	manifest := make(map[string]ManifestEntry, len(entries))
+	for _, e := range entries {
+		manifest[e.Key] = e
+	}
+	return manifest, nil
+}
+
+func (u *Uploader) saveManifest() error {
+	u.mu.Lock()
+	entries := make([]ManifestEntry, 0, len(u.manifest))
+	for _, e := range u.manifest {
+		entries = append(entries, e)
+	}
+	u.mu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := u.cfg.ManifestPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.cfg.ManifestPath)
+}
@@ -0,0 +1,143 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// TestUploadFilesDoesNotDeadlockOnPersistentError guards against the
+// regression where a worker returned (instead of continuing to drain
+// jobCh) on the first upload error: once every worker had exited, the
+// unbuffered jobCh had no receiver left and the producer loop hung
+// forever instead of returning the error.
+func TestUploadFilesDoesNotDeadlockOnPersistentError(t *testing.T) {
+	// Nothing listens on 127.0.0.1:1, so every upload attempt fails fast
+	// with a connection error rather than hanging or needing real AWS
+	// access.
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://127.0.0.1:1"),
+		DisableSSL:       aws.Bool(true),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	})
+	if err != nil {
+		t.Fatalf("session.NewSession: %v", err)
+	}
+
+	u, err := New(sess, Config{
+		Bucket:       "test-bucket",
+		Concurrency:  2,
+		ManifestPath: filepath.Join(t.TempDir(), "manifest.json"),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	dir := t.TempDir()
+	var jobs []fileJob
+	for i := 0; i < 6; i++ { // more files than Concurrency
+		path := filepath.Join(dir, fmt.Sprintf("f%d.txt", i))
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		jobs = append(jobs, fileJob{path: path, key: fmt.Sprintf("f%d.txt", i), info: info})
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- u.uploadFiles(context.Background(), jobs) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected uploadFiles to return an error, got nil")
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("uploadFiles did not return — deadlock on persistent upload errors")
+	}
+}
+
+// TestUploadFilesSavesManifestOnError guards against the regression where
+// the manifest was only saved on the error-free path: a partial-failure
+// batch returned firstErr before ever calling saveManifest, so a file that
+// completed before the error was forgotten and re-uploaded on rerun.
+func TestUploadFilesSavesManifestOnError(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://127.0.0.1:1"),
+		DisableSSL:       aws.Bool(true),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	})
+	if err != nil {
+		t.Fatalf("session.NewSession: %v", err)
+	}
+
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	u, err := New(sess, Config{
+		Bucket:       "test-bucket",
+		Concurrency:  2,
+		ManifestPath: manifestPath,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Seed the in-memory manifest as if an earlier run had already
+	// uploaded this file, so we can tell whether uploadFiles persists it
+	// to disk even though every job below fails.
+	dir := t.TempDir()
+	donePath := filepath.Join(dir, "already-done.txt")
+	if err := os.WriteFile(donePath, []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	doneInfo, err := os.Stat(donePath)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	u.manifest["already-done.txt"] = ManifestEntry{
+		Key:   "already-done.txt",
+		Size:  doneInfo.Size(),
+		MTime: doneInfo.ModTime(),
+	}
+
+	failPath := filepath.Join(dir, "fails.txt")
+	if err := os.WriteFile(failPath, []byte("y"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	failInfo, err := os.Stat(failPath)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	jobs := []fileJob{{path: failPath, key: "fails.txt", info: failInfo}}
+
+	if err := u.uploadFiles(context.Background(), jobs); err == nil {
+		t.Fatal("expected uploadFiles to return an error, got nil")
+	}
+
+	if _, err := os.Stat(manifestPath); err != nil {
+		t.Fatalf("manifest was not saved on the error path: %v", err)
+	}
+
+	reloaded, err := New(sess, Config{
+		Bucket:       "test-bucket",
+		ManifestPath: manifestPath,
+	})
+	if err != nil {
+		t.Fatalf("New (reload): %v", err)
+	}
+	if !reloaded.alreadyUploaded("already-done.txt", doneInfo) {
+		t.Error("expected already-done.txt to be skipped on rerun after reloading the manifest")
+	}
+}
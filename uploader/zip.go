@@ -0,0 +1,129 @@
+package uploader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// nestedZipMemoryThreshold is the largest nested zip entry we'll buffer
+// fully in memory to get the io.ReaderAt archive/zip needs. Anything
+// larger is buffered to a temp file instead.
+const nestedZipMemoryThreshold = 32 << 20 // 32 MiB
+
+// UploadZip uploads the contents of the zip archive at zipPath straight
+// to S3 under prefix, without ever extracting it to disk: each entry is
+// streamed from the archive directly into the multipart upload. Nested
+// zip entries are expanded in place (buffered in memory when small, or
+// to a temp file otherwise, since archive/zip needs random access) and
+// their contents are uploaded the same way.
+func (u *Uploader) UploadZip(ctx context.Context, zipPath, prefix string) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return u.uploadZipEntries(ctx, &r.Reader, prefix)
+}
+
+func (u *Uploader) uploadZipEntries(ctx context.Context, zr *zip.Reader, prefix string) error {
+	for _, file := range zr.File {
+		if strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		if strings.HasSuffix(file.Name, ".zip") {
+			if err := u.uploadNestedZip(ctx, file, prefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := filepath.ToSlash(filepath.Join(prefix, file.Name))
+		if err := u.uploadZipEntry(ctx, file, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *Uploader) uploadZipEntry(ctx context.Context, file *zip.File, key string) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(u.cfg.Bucket),
+		Key:    aws.String(key),
+		Body:   rc,
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(file.Name)); ct != "" {
+		input.ContentType = aws.String(ct)
+	}
+
+	_, err = u.s3.UploadWithContext(ctx, input)
+	return err
+}
+
+// uploadNestedZip expands a nested zip entry into a *zip.Reader and
+// uploads its contents under prefix joined with the entry's directory,
+// mirroring how extractZipFiles flattens nested archives into the
+// directory of their parent rather than a subdirectory named after the zip.
+func (u *Uploader) uploadNestedZip(ctx context.Context, file *zip.File, prefix string) error {
+	prefix = filepath.Join(prefix, filepath.Dir(file.Name))
+
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if file.UncompressedSize64 <= nestedZipMemoryThreshold {
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		return u.uploadZipEntries(ctx, zr, prefix)
+	}
+
+	tmp, err := os.CreateTemp("", "s3uploader-nested-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, rc); err != nil {
+		return err
+	}
+
+	fi, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(tmp, fi.Size())
+	if err != nil {
+		return err
+	}
+
+	return u.uploadZipEntries(ctx, zr, prefix)
+}